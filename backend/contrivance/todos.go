@@ -1,12 +1,23 @@
 package main
 
 import (
+    "bytes"
+    "database/sql"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
     "net/http"
     "strconv"
+    "strings"
+    "sync"
     "time"
 
     "github.com/gin-gonic/gin"
     "github.com/google/uuid"
+    "github.com/jmoiron/sqlx"
+    "github.com/lib/pq"
 )
 
 // Todo represents a todo item in the system
@@ -23,6 +34,21 @@ type Todo struct {
     SpreadsheetID    string     `json:"spreadsheet_id" db:"spreadsheet_id" binding:"required"`
     RowID            *string    `json:"row_id" db:"row_id"`
     UserID           string     `json:"user_id" db:"user_id"`
+    RecurrenceRule   *string    `json:"recurrence_rule,omitempty" db:"recurrence_rule"`
+    RecurrenceEnd    *time.Time `json:"recurrence_end,omitempty" db:"recurrence_end"`
+    ParentID         *string    `json:"parent_id,omitempty" db:"parent_id"`
+    DeletedAt        *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// TodoAuditEntry is a single row in the todo_audit table, recording who did
+// what to a todo and when.
+type TodoAuditEntry struct {
+    ID        string    `json:"id" db:"id"`
+    TodoID    string    `json:"todo_id" db:"todo_id"`
+    UserID    string    `json:"user_id" db:"user_id"`
+    Action    string    `json:"action" db:"action"`
+    Diff      string    `json:"diff" db:"diff"`
+    CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
 // CreateTodoRequest represents the request body for creating todos
@@ -34,6 +60,9 @@ type CreateTodoRequest struct {
     SupportingArtifact string   `json:"supporting_artifact"`
     SpreadsheetID    string     `json:"spreadsheet_id" binding:"required"`
     RowID            *string    `json:"row_id"`
+    RecurrenceRule   *string    `json:"recurrence_rule"`
+    RecurrenceEnd    *time.Time `json:"recurrence_end"`
+    ParentID         *string    `json:"parent_id"`
 }
 
 // UpdateTodoRequest represents the request body for updating todos
@@ -44,6 +73,40 @@ type UpdateTodoRequest struct {
     Completed         *bool      `json:"completed"`
     DueDate          *time.Time `json:"due_date"`
     SupportingArtifact *string   `json:"supporting_artifact"`
+    RecurrenceRule    *string    `json:"recurrence_rule"`
+    RecurrenceEnd     *time.Time `json:"recurrence_end"`
+}
+
+// BulkItemResult reports the per-item outcome of a bulk todo operation.
+type BulkItemResult struct {
+    ID     string `json:"id,omitempty"`
+    Status string `json:"status"`
+    Error  string `json:"error,omitempty"`
+}
+
+// BulkCreateRequest is the request body for POST /todos/bulk.
+type BulkCreateRequest struct {
+    Todos []CreateTodoRequest `json:"todos" binding:"required"`
+}
+
+// BulkPatchFilter selects todos to patch by spreadsheet and priority instead
+// of by explicit ID.
+type BulkPatchFilter struct {
+    SpreadsheetID string `json:"spreadsheet_id" binding:"required"`
+    Priority      string `json:"priority" binding:"required"`
+}
+
+// BulkPatchRequest is the request body for PATCH /todos/bulk. Exactly one of
+// IDs or Filter should be set to select which todos Updates applies to.
+type BulkPatchRequest struct {
+    IDs     []string         `json:"ids"`
+    Filter  *BulkPatchFilter `json:"filter"`
+    Updates UpdateTodoRequest `json:"updates"`
+}
+
+// BulkDeleteRequest is the request body for DELETE /todos/bulk.
+type BulkDeleteRequest struct {
+    IDs []string `json:"ids" binding:"required"`
 }
 
 // TodoStats represents aggregated todo statistics
@@ -60,15 +123,25 @@ type TodoStats struct {
 func setupTodoRoutes(r *gin.RouterGroup) {
     todos := r.Group("/todos")
     {
-        todos.POST("/", createTodo)
+        todos.POST("/", quotaMiddleware(), createTodo)
+        todos.POST("/bulk", quotaMiddleware(), createTodosBulk)
+        todos.PATCH("/bulk", quotaMiddleware(), patchTodosBulk)
+        todos.DELETE("/bulk", quotaMiddleware(), deleteTodosBulk)
+        todos.GET("/trash", getTrashedTodos)
+        todos.GET("/quota", getTodoQuota)
         todos.GET("/spreadsheet/:spreadsheet_id", getTodosBySpreadsheet)
         todos.GET("/spreadsheet/:spreadsheet_id/row/:row_id", getTodosByRow)
         todos.GET("/spreadsheet/:spreadsheet_id/stats", getTodoStats)
+        todos.GET("/spreadsheet/:spreadsheet_id/upcoming", getUpcomingTodos)
+        todos.GET("/spreadsheet/:spreadsheet_id/events", getTodoEvents)
         todos.GET("/:id", getTodoByID)
-        todos.PUT("/:id", updateTodo)
+        todos.PUT("/:id", quotaMiddleware(), updateTodo)
         todos.DELETE("/:id", deleteTodo)
         todos.PUT("/:id/complete", completeTodo)
         todos.PUT("/:id/uncomplete", uncompleteTodo)
+        todos.PUT("/:id/restore", restoreTodo)
+        todos.DELETE("/:id/purge", purgeTodo)
+        todos.GET("/:id/history", getTodoHistory)
     }
 }
 
@@ -88,11 +161,18 @@ func createTodo(c *gin.Context) {
     }
 
     // Validate priority
-    if req.Priority != "low" && req.Priority != "medium" && req.Priority != "high" {
+    if !validTodoPriority(req.Priority) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Priority must be 'low', 'medium', or 'high'"})
         return
     }
 
+    if req.RecurrenceRule != nil {
+        if _, err := parseRRule(*req.RecurrenceRule); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+    }
+
     todo := Todo{
         ID:                uuid.New().String(),
         Title:             req.Title,
@@ -106,28 +186,39 @@ func createTodo(c *gin.Context) {
         SpreadsheetID:    req.SpreadsheetID,
         RowID:            req.RowID,
         UserID:           userID.(string),
+        RecurrenceRule:   req.RecurrenceRule,
+        RecurrenceEnd:    req.RecurrenceEnd,
+        ParentID:         req.ParentID,
     }
 
     query := `
-        INSERT INTO todos (id, title, description, priority, completed, created_at, updated_at, due_date, supporting_artifact, spreadsheet_id, row_id, user_id)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        INSERT INTO todos (id, title, description, priority, completed, created_at, updated_at, due_date, supporting_artifact, spreadsheet_id, row_id, user_id, recurrence_rule, recurrence_end, parent_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
         RETURNING *`
 
     var createdTodo Todo
-    err := db.Get(&createdTodo, query, 
+    err := db.Get(&createdTodo, query,
         todo.ID, todo.Title, todo.Description, todo.Priority, todo.Completed,
         todo.CreatedAt, todo.UpdatedAt, todo.DueDate, todo.SupportingArtifact,
-        todo.SpreadsheetID, todo.RowID, todo.UserID)
+        todo.SpreadsheetID, todo.RowID, todo.UserID,
+        todo.RecurrenceRule, todo.RecurrenceEnd, todo.ParentID)
 
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create todo"})
         return
     }
 
+    if err := recordTodoAudit(db, userID.(string), createdTodo.ID, "create", todoCreateDiff(req)); err != nil {
+        log.Printf("failed to record audit entry for todo %s: %v", createdTodo.ID, err)
+    }
+
+    todoEvents.Publish(TodoEvent{Type: "created", SpreadsheetID: createdTodo.SpreadsheetID, UserID: createdTodo.UserID, Todo: &createdTodo})
+
     c.JSON(http.StatusCreated, createdTodo)
 }
 
-// getTodosBySpreadsheet gets all todos for a spreadsheet
+// getTodosBySpreadsheet gets todos for a spreadsheet, paginated by default.
+// Pass ?legacy=1 to get the old bare-array response instead of {items, next_cursor}.
 func getTodosBySpreadsheet(c *gin.Context) {
     spreadsheetID := c.Param("spreadsheet_id")
     userID, exists := c.Get("user_id")
@@ -136,26 +227,45 @@ func getTodosBySpreadsheet(c *gin.Context) {
         return
     }
 
-    var todos []Todo
-    query := `
-        SELECT * FROM todos 
-        WHERE spreadsheet_id = $1 AND user_id = $2 AND row_id IS NULL
-        ORDER BY created_at DESC`
+    if c.Query("legacy") == "1" {
+        var todos []Todo
+        query := `
+            SELECT * FROM todos
+            WHERE spreadsheet_id = $1 AND user_id = $2 AND row_id IS NULL AND deleted_at IS NULL
+            ORDER BY created_at DESC`
 
-    err := db.Select(&todos, query, spreadsheetID, userID.(string))
+        if err := db.Select(&todos, query, spreadsheetID, userID.(string)); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
+            return
+        }
+        if todos == nil {
+            todos = []Todo{}
+        }
+        c.JSON(http.StatusOK, todos)
+        return
+    }
+
+    params, err := parseTodoListParams(c)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
-    if todos == nil {
-        todos = []Todo{}
+    query, args := buildTodoListQuery(
+        "spreadsheet_id = $1 AND user_id = $2 AND row_id IS NULL",
+        []interface{}{spreadsheetID, userID.(string)}, params)
+
+    var todos []Todo
+    if err := db.Select(&todos, query, args...); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
+        return
     }
 
-    c.JSON(http.StatusOK, todos)
+    c.JSON(http.StatusOK, paginateTodos(todos, params.Limit, params.SortColumn))
 }
 
-// getTodosByRow gets all todos for a specific row
+// getTodosByRow gets todos for a specific row, paginated by default. Pass
+// ?legacy=1 to get the old bare-array response instead of {items, next_cursor}.
 func getTodosByRow(c *gin.Context) {
     spreadsheetID := c.Param("spreadsheet_id")
     rowID := c.Param("row_id")
@@ -165,23 +275,41 @@ func getTodosByRow(c *gin.Context) {
         return
     }
 
-    var todos []Todo
-    query := `
-        SELECT * FROM todos 
-        WHERE spreadsheet_id = $1 AND row_id = $2 AND user_id = $3
-        ORDER BY created_at DESC`
+    if c.Query("legacy") == "1" {
+        var todos []Todo
+        query := `
+            SELECT * FROM todos
+            WHERE spreadsheet_id = $1 AND row_id = $2 AND user_id = $3 AND deleted_at IS NULL
+            ORDER BY created_at DESC`
+
+        if err := db.Select(&todos, query, spreadsheetID, rowID, userID.(string)); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
+            return
+        }
+        if todos == nil {
+            todos = []Todo{}
+        }
+        c.JSON(http.StatusOK, todos)
+        return
+    }
 
-    err := db.Select(&todos, query, spreadsheetID, rowID, userID.(string))
+    params, err := parseTodoListParams(c)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
-    if todos == nil {
-        todos = []Todo{}
+    query, args := buildTodoListQuery(
+        "spreadsheet_id = $1 AND row_id = $2 AND user_id = $3",
+        []interface{}{spreadsheetID, rowID, userID.(string)}, params)
+
+    var todos []Todo
+    if err := db.Select(&todos, query, args...); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
+        return
     }
 
-    c.JSON(http.StatusOK, todos)
+    c.JSON(http.StatusOK, paginateTodos(todos, params.Limit, params.SortColumn))
 }
 
 // getTodoStats gets aggregated todo statistics for a spreadsheet
@@ -202,8 +330,8 @@ func getTodoStats(c *gin.Context) {
             COUNT(CASE WHEN priority = 'high' THEN 1 END) as high_priority,
             COUNT(CASE WHEN priority = 'medium' THEN 1 END) as medium_priority,
             COUNT(CASE WHEN priority = 'low' THEN 1 END) as low_priority
-        FROM todos 
-        WHERE spreadsheet_id = $1 AND user_id = $2`
+        FROM todos
+        WHERE spreadsheet_id = $1 AND user_id = $2 AND deleted_at IS NULL`
 
     err := db.Get(&stats, query, spreadsheetID, userID.(string))
     if err != nil {
@@ -224,7 +352,7 @@ func getTodoByID(c *gin.Context) {
     }
 
     var todo Todo
-    query := `SELECT * FROM todos WHERE id = $1 AND user_id = $2`
+    query := `SELECT * FROM todos WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
 
     err := db.Get(&todo, query, todoID, userID.(string))
     if err != nil {
@@ -251,47 +379,19 @@ func updateTodo(c *gin.Context) {
     }
 
     // Validate priority if provided
-    if req.Priority != nil && *req.Priority != "low" && *req.Priority != "medium" && *req.Priority != "high" {
+    if req.Priority != nil && !validTodoPriority(*req.Priority) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Priority must be 'low', 'medium', or 'high'"})
         return
     }
-
-    // Build dynamic update query
-    updates := []string{}
-    args := []interface{}{todoID, userID.(string)}
-    argIndex := 3
-
-    if req.Title != nil {
-        updates = append(updates, "title = $"+strconv.Itoa(argIndex))
-        args = append(args, *req.Title)
-        argIndex++
-    }
-    if req.Description != nil {
-        updates = append(updates, "description = $"+strconv.Itoa(argIndex))
-        args = append(args, *req.Description)
-        argIndex++
-    }
-    if req.Priority != nil {
-        updates = append(updates, "priority = $"+strconv.Itoa(argIndex))
-        args = append(args, *req.Priority)
-        argIndex++
-    }
-    if req.Completed != nil {
-        updates = append(updates, "completed = $"+strconv.Itoa(argIndex))
-        args = append(args, *req.Completed)
-        argIndex++
-    }
-    if req.DueDate != nil {
-        updates = append(updates, "due_date = $"+strconv.Itoa(argIndex))
-        args = append(args, *req.DueDate)
-        argIndex++
-    }
-    if req.SupportingArtifact != nil {
-        updates = append(updates, "supporting_artifact = $"+strconv.Itoa(argIndex))
-        args = append(args, *req.SupportingArtifact)
-        argIndex++
+    if req.RecurrenceRule != nil {
+        if _, err := parseRRule(*req.RecurrenceRule); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
     }
 
+    // Build dynamic update query
+    updates, updateArgs := buildTodoUpdateSet(req, 3)
     if len(updates) == 0 {
         c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
         return
@@ -300,10 +400,12 @@ func updateTodo(c *gin.Context) {
     // Always update the updated_at timestamp
     updates = append(updates, "updated_at = CURRENT_TIMESTAMP")
 
+    args := append([]interface{}{todoID, userID.(string)}, updateArgs...)
+
     query := `
-        UPDATE todos 
+        UPDATE todos
         SET ` + strings.Join(updates, ", ") + `
-        WHERE id = $1 AND user_id = $2
+        WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
         RETURNING *`
 
     var updatedTodo Todo
@@ -313,10 +415,17 @@ func updateTodo(c *gin.Context) {
         return
     }
 
+    if err := recordTodoAudit(db, userID.(string), updatedTodo.ID, "update", todoUpdateDiff(req)); err != nil {
+        log.Printf("failed to record audit entry for todo %s: %v", updatedTodo.ID, err)
+    }
+
+    todoEvents.Publish(TodoEvent{Type: "updated", SpreadsheetID: updatedTodo.SpreadsheetID, UserID: updatedTodo.UserID, Todo: &updatedTodo})
+
     c.JSON(http.StatusOK, updatedTodo)
 }
 
-// deleteTodo deletes a specific todo
+// deleteTodo soft-deletes a specific todo by stamping deleted_at, so it can
+// later be restored or purged. See restoreTodo and purgeTodo.
 func deleteTodo(c *gin.Context) {
     todoID := c.Param("id")
     userID, exists := c.Get("user_id")
@@ -325,20 +434,141 @@ func deleteTodo(c *gin.Context) {
         return
     }
 
-    query := `DELETE FROM todos WHERE id = $1 AND user_id = $2`
+    query := `
+        UPDATE todos
+        SET deleted_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+        RETURNING spreadsheet_id`
+
+    var spreadsheetID string
+    if err := db.Get(&spreadsheetID, query, todoID, userID.(string)); err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+        return
+    }
+
+    if err := recordTodoAudit(db, userID.(string), todoID, "delete", map[string]interface{}{"deleted": true}); err != nil {
+        log.Printf("failed to record audit entry for todo %s: %v", todoID, err)
+    }
+
+    todoEvents.Publish(TodoEvent{Type: "deleted", SpreadsheetID: spreadsheetID, UserID: userID.(string), TodoID: todoID})
+
+    c.JSON(http.StatusOK, gin.H{"message": "Todo deleted successfully"})
+}
+
+// restoreTodo clears deleted_at on a soft-deleted todo.
+func restoreTodo(c *gin.Context) {
+    todoID := c.Param("id")
+    userID, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+        return
+    }
+
+    query := `
+        UPDATE todos
+        SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+        RETURNING *`
+
+    var todo Todo
+    err := db.Get(&todo, query, todoID, userID.(string))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found in trash"})
+        return
+    }
+
+    if err := recordTodoAudit(db, userID.(string), todo.ID, "restore", map[string]interface{}{"deleted": false}); err != nil {
+        log.Printf("failed to record audit entry for todo %s: %v", todo.ID, err)
+    }
+
+    c.JSON(http.StatusOK, todo)
+}
+
+// purgeTodo permanently deletes a soft-deleted todo. It refuses to purge a
+// todo that hasn't been soft-deleted first, to keep hard deletes intentional.
+func purgeTodo(c *gin.Context) {
+    todoID := c.Param("id")
+    userID, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+        return
+    }
+
+    query := `DELETE FROM todos WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL`
     result, err := db.Exec(query, todoID, userID.(string))
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete todo"})
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge todo"})
         return
     }
 
     rowsAffected, _ := result.RowsAffected()
     if rowsAffected == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found in trash"})
+        return
+    }
+
+    if err := recordTodoAudit(db, userID.(string), todoID, "purge", map[string]interface{}{"purged": true}); err != nil {
+        log.Printf("failed to record audit entry for todo %s: %v", todoID, err)
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Todo purged successfully"})
+}
+
+// getTrashedTodos lists the caller's soft-deleted todos.
+func getTrashedTodos(c *gin.Context) {
+    userID, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+        return
+    }
+
+    var todos []Todo
+    query := `
+        SELECT * FROM todos
+        WHERE user_id = $1 AND deleted_at IS NOT NULL
+        ORDER BY deleted_at DESC`
+
+    if err := db.Select(&todos, query, userID.(string)); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trash"})
+        return
+    }
+    if todos == nil {
+        todos = []Todo{}
+    }
+
+    c.JSON(http.StatusOK, todos)
+}
+
+// getTodoHistory returns the audit trail for a single todo, most recent first.
+func getTodoHistory(c *gin.Context) {
+    todoID := c.Param("id")
+    userID, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+        return
+    }
+
+    var owned string
+    if err := db.Get(&owned, `SELECT id FROM todos WHERE id = $1 AND user_id = $2`, todoID, userID.(string)); err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"message": "Todo deleted successfully"})
+    var entries []TodoAuditEntry
+    query := `
+        SELECT * FROM todo_audit
+        WHERE todo_id = $1
+        ORDER BY created_at DESC`
+
+    if err := db.Select(&entries, query, todoID); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo history"})
+        return
+    }
+    if entries == nil {
+        entries = []TodoAuditEntry{}
+    }
+
+    c.JSON(http.StatusOK, entries)
 }
 
 // completeTodo marks a todo as completed
@@ -351,9 +581,9 @@ func completeTodo(c *gin.Context) {
     }
 
     query := `
-        UPDATE todos 
-        SET completed = true, updated_at = CURRENT_TIMESTAMP 
-        WHERE id = $1 AND user_id = $2
+        UPDATE todos
+        SET completed = true, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
         RETURNING *`
 
     var todo Todo
@@ -363,6 +593,18 @@ func completeTodo(c *gin.Context) {
         return
     }
 
+    if err := recordTodoAudit(db, userID.(string), todo.ID, "complete", map[string]interface{}{"completed": true}); err != nil {
+        log.Printf("failed to record audit entry for todo %s: %v", todo.ID, err)
+    }
+
+    todoEvents.Publish(TodoEvent{Type: "completed", SpreadsheetID: todo.SpreadsheetID, UserID: todo.UserID, Todo: &todo})
+
+    if todo.RecurrenceRule != nil && *todo.RecurrenceRule != "" {
+        if err := scheduleNextOccurrence(todo); err != nil {
+            log.Printf("failed to schedule next occurrence for todo %s: %v", todo.ID, err)
+        }
+    }
+
     c.JSON(http.StatusOK, todo)
 }
 
@@ -376,9 +618,9 @@ func uncompleteTodo(c *gin.Context) {
     }
 
     query := `
-        UPDATE todos 
-        SET completed = false, updated_at = CURRENT_TIMESTAMP 
-        WHERE id = $1 AND user_id = $2
+        UPDATE todos
+        SET completed = false, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
         RETURNING *`
 
     var todo Todo
@@ -388,5 +630,1591 @@ func uncompleteTodo(c *gin.Context) {
         return
     }
 
+    if err := recordTodoAudit(db, userID.(string), todo.ID, "uncomplete", map[string]interface{}{"completed": false}); err != nil {
+        log.Printf("failed to record audit entry for todo %s: %v", todo.ID, err)
+    }
+
+    todoEvents.Publish(TodoEvent{Type: "updated", SpreadsheetID: todo.SpreadsheetID, UserID: todo.UserID, Todo: &todo})
+
     c.JSON(http.StatusOK, todo)
-}
\ No newline at end of file
+}
+
+// getUpcomingTodos materializes virtual occurrences of recurring todos within
+// a [from, to] window without writing them to the database, so clients can
+// render a calendar view without paying for a scheduler round-trip.
+func getUpcomingTodos(c *gin.Context) {
+    spreadsheetID := c.Param("spreadsheet_id")
+    userID, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+        return
+    }
+
+    from, err := time.Parse(time.RFC3339, c.Query("from"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing 'from' query parameter, expected RFC3339"})
+        return
+    }
+    to, err := time.Parse(time.RFC3339, c.Query("to"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing 'to' query parameter, expected RFC3339"})
+        return
+    }
+    if to.Before(from) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "'to' must not be before 'from'"})
+        return
+    }
+
+    var templates []Todo
+    query := `
+        SELECT * FROM todos
+        WHERE spreadsheet_id = $1 AND user_id = $2 AND recurrence_rule IS NOT NULL AND parent_id IS NULL AND deleted_at IS NULL
+        ORDER BY created_at DESC`
+
+    if err := db.Select(&templates, query, spreadsheetID, userID.(string)); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recurring todos"})
+        return
+    }
+
+    occurrences := []Todo{}
+    for _, tmpl := range templates {
+        if tmpl.DueDate == nil || tmpl.RecurrenceRule == nil {
+            continue
+        }
+
+        rule, err := parseRRule(*tmpl.RecurrenceRule)
+        if err != nil {
+            continue
+        }
+        if tmpl.RecurrenceEnd != nil && (rule.Until == nil || tmpl.RecurrenceEnd.Before(*rule.Until)) {
+            rule.Until = tmpl.RecurrenceEnd
+        }
+
+        seriesStart := *tmpl.DueDate
+        anchor := seriesStart
+        occurrenceIndex := 0
+        for i := 0; i < maxMaterializedOccurrences; i++ {
+            next, ok := rule.nextOccurrence(anchor, seriesStart, occurrenceIndex)
+            if !ok || next.After(to) {
+                break
+            }
+            occurrenceIndex++
+            anchor = next
+            if next.Before(from) {
+                continue
+            }
+
+            virtual := tmpl
+            due := next
+            parentID := tmpl.ID
+            virtual.ID = fmt.Sprintf("%s-occurrence-%s", tmpl.ID, next.UTC().Format("20060102T150405Z"))
+            virtual.DueDate = &due
+            virtual.ParentID = &parentID
+            occurrences = append(occurrences, virtual)
+        }
+    }
+
+    c.JSON(http.StatusOK, occurrences)
+}
+
+// maxMaterializedOccurrences bounds how many occurrences getUpcomingTodos
+// will step through for a single recurring todo, counted from the series'
+// first due date, as a safety valve against pathological rules (e.g. a
+// narrow BYDAY filter far from the window). Occurrences before 'from' count
+// toward the bound even though they are not returned. nextOccurrence uses
+// the same value to bound its search for a single matching day.
+const maxMaterializedOccurrences = 1000
+
+// rrule is a parsed, minimal subset of an RFC 5545 recurrence rule: FREQ of
+// DAILY, WEEKLY, MONTHLY, or YEARLY, with INTERVAL, BYDAY, BYMONTHDAY, COUNT,
+// and UNTIL.
+type rrule struct {
+    Freq       string
+    Interval   int
+    ByDay      []time.Weekday
+    ByMonthDay []int
+    Count      int
+    Until      *time.Time
+}
+
+var rruleDayCodes = map[string]time.Weekday{
+    "SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+    "TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRRule parses a recurrence_rule string such as
+// "FREQ=WEEKLY;BYDAY=MO,WE;INTERVAL=1;UNTIL=20251231T000000Z".
+func parseRRule(rule string) (*rrule, error) {
+    r := &rrule{Interval: 1}
+
+    for _, part := range strings.Split(rule, ";") {
+        if part == "" {
+            continue
+        }
+        kv := strings.SplitN(part, "=", 2)
+        if len(kv) != 2 {
+            return nil, fmt.Errorf("invalid recurrence rule segment: %q", part)
+        }
+        key, value := strings.ToUpper(kv[0]), kv[1]
+
+        switch key {
+        case "FREQ":
+            switch value {
+            case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+                r.Freq = value
+            default:
+                return nil, fmt.Errorf("unsupported recurrence FREQ: %q", value)
+            }
+        case "INTERVAL":
+            n, err := strconv.Atoi(value)
+            if err != nil || n <= 0 {
+                return nil, fmt.Errorf("invalid recurrence INTERVAL: %q", value)
+            }
+            r.Interval = n
+        case "BYDAY":
+            for _, code := range strings.Split(value, ",") {
+                wd, ok := rruleDayCodes[code]
+                if !ok {
+                    return nil, fmt.Errorf("invalid recurrence BYDAY code: %q", code)
+                }
+                r.ByDay = append(r.ByDay, wd)
+            }
+        case "BYMONTHDAY":
+            for _, d := range strings.Split(value, ",") {
+                n, err := strconv.Atoi(d)
+                if err != nil {
+                    return nil, fmt.Errorf("invalid recurrence BYMONTHDAY value: %q", d)
+                }
+                r.ByMonthDay = append(r.ByMonthDay, n)
+            }
+        case "COUNT":
+            n, err := strconv.Atoi(value)
+            if err != nil || n <= 0 {
+                return nil, fmt.Errorf("invalid recurrence COUNT: %q", value)
+            }
+            r.Count = n
+        case "UNTIL":
+            until, err := time.Parse("20060102T150405Z", value)
+            if err != nil {
+                return nil, fmt.Errorf("invalid recurrence UNTIL value: %q", value)
+            }
+            r.Until = &until
+        }
+    }
+
+    if r.Freq == "" {
+        return nil, fmt.Errorf("recurrence rule is missing FREQ")
+    }
+    return r, nil
+}
+
+// nextOccurrence returns the next time strictly after anchor that satisfies
+// the rule, or false if the series is exhausted (COUNT reached or UNTIL
+// passed). seriesStart is the series' original anchor (its first due date),
+// used as the reference point for INTERVAL under WEEKLY+BYDAY and
+// MONTHLY+BYMONTHDAY, so "every other Monday" stays pinned to the week the
+// series actually started rather than drifting from whichever occurrence
+// happened to complete last. occurrencesSoFar is the number of instances
+// already materialized for this series *after* its first one (seriesStart),
+// used to enforce COUNT; per RFC 5545, COUNT includes that first instance
+// as occurrence #1, so it is implicitly added to occurrencesSoFar here
+// rather than requiring every caller to seed it at 1. Stepping is done with
+// time.AddDate, which preserves wall-clock time across DST transitions in
+// anchor's location rather than adding a fixed duration.
+func (r *rrule) nextOccurrence(anchor, seriesStart time.Time, occurrencesSoFar int) (time.Time, bool) {
+    if r.Count > 0 && occurrencesSoFar+1 >= r.Count {
+        return time.Time{}, false
+    }
+
+    candidate := anchor
+    for i := 0; i < maxMaterializedOccurrences; i++ {
+        candidate = r.advance(candidate)
+        if r.Until != nil && candidate.After(*r.Until) {
+            return time.Time{}, false
+        }
+        if r.matches(candidate, seriesStart) {
+            return candidate, true
+        }
+    }
+    return time.Time{}, false
+}
+
+// advance steps a candidate time forward by one period of the rule's FREQ
+// times INTERVAL (days, weeks, months, or years). For WEEKLY with BYDAY and
+// MONTHLY with BYMONTHDAY it instead steps by one day: matches() then picks
+// out the qualifying days and uses periodStart to tell which week/month is
+// actually due under INTERVAL.
+func (r *rrule) advance(t time.Time) time.Time {
+    switch r.Freq {
+    case "DAILY":
+        return t.AddDate(0, 0, r.Interval)
+    case "WEEKLY":
+        if len(r.ByDay) == 0 {
+            return t.AddDate(0, 0, 7*r.Interval)
+        }
+        return t.AddDate(0, 0, 1)
+    case "MONTHLY":
+        if len(r.ByMonthDay) == 0 {
+            return t.AddDate(0, r.Interval, 0)
+        }
+        return t.AddDate(0, 0, 1)
+    case "YEARLY":
+        return t.AddDate(r.Interval, 0, 0)
+    default:
+        return t.AddDate(0, 0, 1)
+    }
+}
+
+// matches reports whether t satisfies the rule's BYDAY/BYMONTHDAY filters and
+// falls on a week/month that is actually due under INTERVAL, relative to
+// periodStart (the series' original anchor). For WEEKLY+BYDAY, INTERVAL
+// counts weeks since periodStart's week; for MONTHLY+BYMONTHDAY, it counts
+// months since periodStart's month.
+func (r *rrule) matches(t time.Time, periodStart time.Time) bool {
+    if len(r.ByDay) > 0 {
+        found := false
+        for _, wd := range r.ByDay {
+            if t.Weekday() == wd {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return false
+        }
+        if r.Interval > 1 && r.Freq == "WEEKLY" {
+            weeksSince := weeksBetween(periodStart, t)
+            if weeksSince%r.Interval != 0 {
+                return false
+            }
+        }
+    }
+    if len(r.ByMonthDay) > 0 {
+        found := false
+        for _, d := range r.ByMonthDay {
+            if t.Day() == d {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return false
+        }
+        if r.Interval > 1 && r.Freq == "MONTHLY" {
+            monthsSince := (t.Year()-periodStart.Year())*12 + int(t.Month()) - int(periodStart.Month())
+            if monthsSince%r.Interval != 0 {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+// weeksBetween returns the number of ISO week boundaries (Monday-start)
+// between from and to, used to enforce WEEKLY;BYDAY;INTERVAL.
+func weeksBetween(from, to time.Time) int {
+    daysSinceMondayFrom := (int(from.Weekday()) + 6) % 7
+    daysSinceMondayTo := (int(to.Weekday()) + 6) % 7
+    // Compare calendar dates at UTC midnight so a DST shift or a differing
+    // time of day between from and to can't shave an hour off a whole week.
+    fromMonday := time.Date(from.Year(), from.Month(), from.Day()-daysSinceMondayFrom, 0, 0, 0, 0, time.UTC)
+    toMonday := time.Date(to.Year(), to.Month(), to.Day()-daysSinceMondayTo, 0, 0, 0, 0, time.UTC)
+    return int(toMonday.Sub(fromMonday).Hours() / (24 * 7))
+}
+
+// scheduleNextOccurrence computes the next due date for a just-completed
+// recurring todo from its recurrence_rule, anchored on its current due date,
+// and inserts the next pending occurrence linked back to the series via
+// parent_id. If the series is exhausted, it inserts nothing and instead
+// marks the series completed via markRecurrenceSeriesCompleted.
+func scheduleNextOccurrence(todo Todo) error {
+    if todo.DueDate == nil {
+        return fmt.Errorf("recurring todo %s has no due date to anchor recurrence from", todo.ID)
+    }
+
+    rule, err := parseRRule(*todo.RecurrenceRule)
+    if err != nil {
+        return err
+    }
+    if todo.RecurrenceEnd != nil && (rule.Until == nil || todo.RecurrenceEnd.Before(*rule.Until)) {
+        rule.Until = todo.RecurrenceEnd
+    }
+
+    parentID := todo.ID
+    if todo.ParentID != nil {
+        parentID = *todo.ParentID
+    }
+
+    var occurrenceCount int
+    if err := db.Get(&occurrenceCount, `SELECT COUNT(*) FROM todos WHERE parent_id = $1 AND deleted_at IS NULL`, parentID); err != nil {
+        return err
+    }
+
+    seriesStart := *todo.DueDate
+    if todo.ParentID != nil {
+        var parentDueDate *time.Time
+        if err := db.Get(&parentDueDate, `SELECT due_date FROM todos WHERE id = $1`, parentID); err != nil {
+            return err
+        }
+        if parentDueDate != nil {
+            seriesStart = *parentDueDate
+        }
+    }
+
+    next, ok := rule.nextOccurrence(*todo.DueDate, seriesStart, occurrenceCount)
+    if !ok {
+        return markRecurrenceSeriesCompleted(parentID, todo.UserID)
+    }
+
+    newTodo := Todo{
+        ID:                 uuid.New().String(),
+        Title:              todo.Title,
+        Description:        todo.Description,
+        Priority:           todo.Priority,
+        Completed:          false,
+        CreatedAt:          time.Now(),
+        UpdatedAt:          time.Now(),
+        DueDate:            &next,
+        SupportingArtifact: todo.SupportingArtifact,
+        SpreadsheetID:      todo.SpreadsheetID,
+        RowID:              todo.RowID,
+        UserID:             todo.UserID,
+        RecurrenceRule:     todo.RecurrenceRule,
+        RecurrenceEnd:      todo.RecurrenceEnd,
+        ParentID:           &parentID,
+    }
+
+    query := `
+        INSERT INTO todos (id, title, description, priority, completed, created_at, updated_at, due_date, supporting_artifact, spreadsheet_id, row_id, user_id, recurrence_rule, recurrence_end, parent_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
+
+    if _, err := db.Exec(query,
+        newTodo.ID, newTodo.Title, newTodo.Description, newTodo.Priority, newTodo.Completed,
+        newTodo.CreatedAt, newTodo.UpdatedAt, newTodo.DueDate, newTodo.SupportingArtifact,
+        newTodo.SpreadsheetID, newTodo.RowID, newTodo.UserID,
+        newTodo.RecurrenceRule, newTodo.RecurrenceEnd, newTodo.ParentID); err != nil {
+        return err
+    }
+
+    if err := recordTodoAudit(db, newTodo.UserID, newTodo.ID, "create", todoOccurrenceCreateDiff(newTodo)); err != nil {
+        log.Printf("failed to record audit entry for todo %s: %v", newTodo.ID, err)
+    }
+
+    todoEvents.Publish(TodoEvent{Type: "created", SpreadsheetID: newTodo.SpreadsheetID, UserID: newTodo.UserID, Todo: &newTodo})
+
+    return nil
+}
+
+// todoOccurrenceCreateDiff builds the audit diff for a recurring todo's
+// auto-generated next occurrence, mirroring todoCreateDiff's shape for the
+// fields that apply to a materialized occurrence.
+func todoOccurrenceCreateDiff(t Todo) map[string]interface{} {
+    diff := map[string]interface{}{
+        "title":          t.Title,
+        "priority":       t.Priority,
+        "spreadsheet_id": t.SpreadsheetID,
+    }
+    if t.Description != "" {
+        diff["description"] = t.Description
+    }
+    if t.DueDate != nil {
+        diff["due_date"] = *t.DueDate
+    }
+    if t.RowID != nil {
+        diff["row_id"] = *t.RowID
+    }
+    if t.RecurrenceRule != nil {
+        diff["recurrence_rule"] = *t.RecurrenceRule
+    }
+    if t.ParentID != nil {
+        diff["parent_id"] = *t.ParentID
+    }
+    return diff
+}
+
+// markRecurrenceSeriesCompleted clears recurrence_rule on a series' root
+// template when the series is exhausted (COUNT reached or UNTIL passed), so
+// it stops being treated as active: scheduleNextOccurrence won't be invoked
+// for it again and getUpcomingTodos will no longer materialize virtual
+// occurrences for it. rootID is the template's own id (todos.parent_id IS
+// NULL for that row).
+func markRecurrenceSeriesCompleted(rootID, userID string) error {
+    _, err := db.Exec(
+        `UPDATE todos SET recurrence_rule = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL`,
+        rootID)
+    if err != nil {
+        return err
+    }
+    return recordTodoAudit(db, userID, rootID, "series_completed", map[string]interface{}{"recurrence_rule": nil})
+}
+
+// validTodoPriority reports whether p is one of the allowed todo priority
+// values. Shared by the single-item and bulk create/update paths so the rule
+// only lives in one place.
+func validTodoPriority(p string) bool {
+    return p == "low" || p == "medium" || p == "high"
+}
+
+// buildTodoUpdateSet builds the SET clause fragments and positional args for
+// a dynamic todos UPDATE from an UpdateTodoRequest, with argument placeholders
+// numbered starting at startIndex. Shared by the single-item and bulk update
+// paths.
+func buildTodoUpdateSet(req UpdateTodoRequest, startIndex int) ([]string, []interface{}) {
+    updates := []string{}
+    args := []interface{}{}
+    argIndex := startIndex
+
+    if req.Title != nil {
+        updates = append(updates, "title = $"+strconv.Itoa(argIndex))
+        args = append(args, *req.Title)
+        argIndex++
+    }
+    if req.Description != nil {
+        updates = append(updates, "description = $"+strconv.Itoa(argIndex))
+        args = append(args, *req.Description)
+        argIndex++
+    }
+    if req.Priority != nil {
+        updates = append(updates, "priority = $"+strconv.Itoa(argIndex))
+        args = append(args, *req.Priority)
+        argIndex++
+    }
+    if req.Completed != nil {
+        updates = append(updates, "completed = $"+strconv.Itoa(argIndex))
+        args = append(args, *req.Completed)
+        argIndex++
+    }
+    if req.DueDate != nil {
+        updates = append(updates, "due_date = $"+strconv.Itoa(argIndex))
+        args = append(args, *req.DueDate)
+        argIndex++
+    }
+    if req.SupportingArtifact != nil {
+        updates = append(updates, "supporting_artifact = $"+strconv.Itoa(argIndex))
+        args = append(args, *req.SupportingArtifact)
+        argIndex++
+    }
+    if req.RecurrenceRule != nil {
+        updates = append(updates, "recurrence_rule = $"+strconv.Itoa(argIndex))
+        args = append(args, *req.RecurrenceRule)
+        argIndex++
+    }
+    if req.RecurrenceEnd != nil {
+        updates = append(updates, "recurrence_end = $"+strconv.Itoa(argIndex))
+        args = append(args, *req.RecurrenceEnd)
+        argIndex++
+    }
+
+    return updates, args
+}
+
+// isBulkAtomic reports whether the caller requested all-or-nothing semantics
+// for a bulk operation via ?atomic=true.
+func isBulkAtomic(c *gin.Context) bool {
+    return c.Query("atomic") == "true"
+}
+
+// withBulkItemSavepoint runs fn as index i of a bulk operation inside its own
+// Postgres SAVEPOINT. A real SQL error aborts only that savepoint, leaving
+// the rest of the surrounding transaction usable - without it, once any
+// tx.Exec fails the whole transaction enters an aborted state and every
+// subsequent item's Exec fails with "current transaction is aborted",
+// silently turning the advertised non-atomic (atomic=false) behavior into an
+// all-or-nothing rollback.
+func withBulkItemSavepoint(tx *sqlx.Tx, i int, fn func() error) error {
+    name := "bulk_item_" + strconv.Itoa(i)
+    if _, err := tx.Exec("SAVEPOINT " + name); err != nil {
+        return err
+    }
+    if err := fn(); err != nil {
+        if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT " + name); rbErr != nil {
+            return rbErr
+        }
+        return err
+    }
+    if _, err := tx.Exec("RELEASE SAVEPOINT " + name); err != nil {
+        return err
+    }
+    return nil
+}
+
+// createTodosBulk creates many todos in a single transaction, reporting
+// per-item success/failure. A partial failure does not roll back the items
+// that succeeded unless the caller passes ?atomic=true.
+func createTodosBulk(c *gin.Context) {
+    var req BulkCreateRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    userID, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+        return
+    }
+
+    if len(req.Todos) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "No todos provided"})
+        return
+    }
+
+    tx, err := db.Beginx()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+        return
+    }
+
+    results := make([]BulkItemResult, 0, len(req.Todos))
+    failed := false
+    failedCount := 0
+
+    for i, item := range req.Todos {
+        if item.Title == "" || item.SpreadsheetID == "" {
+            results = append(results, BulkItemResult{Status: "error", Error: "title and spreadsheet_id are required"})
+            failed = true
+            failedCount++
+            continue
+        }
+        if !validTodoPriority(item.Priority) {
+            results = append(results, BulkItemResult{Status: "error", Error: "Priority must be 'low', 'medium', or 'high'"})
+            failed = true
+            failedCount++
+            continue
+        }
+        if item.RecurrenceRule != nil {
+            if _, err := parseRRule(*item.RecurrenceRule); err != nil {
+                results = append(results, BulkItemResult{Status: "error", Error: err.Error()})
+                failed = true
+                failedCount++
+                continue
+            }
+        }
+
+        todo := Todo{
+            ID:                 uuid.New().String(),
+            Title:              item.Title,
+            Description:        item.Description,
+            Priority:           item.Priority,
+            Completed:          false,
+            CreatedAt:          time.Now(),
+            UpdatedAt:          time.Now(),
+            DueDate:            item.DueDate,
+            SupportingArtifact: item.SupportingArtifact,
+            SpreadsheetID:      item.SpreadsheetID,
+            RowID:              item.RowID,
+            UserID:             userID.(string),
+            RecurrenceRule:     item.RecurrenceRule,
+            RecurrenceEnd:      item.RecurrenceEnd,
+            ParentID:           item.ParentID,
+        }
+
+        query := `
+            INSERT INTO todos (id, title, description, priority, completed, created_at, updated_at, due_date, supporting_artifact, spreadsheet_id, row_id, user_id, recurrence_rule, recurrence_end, parent_id)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
+
+        execErr := withBulkItemSavepoint(tx, i, func() error {
+            _, err := tx.Exec(query,
+                todo.ID, todo.Title, todo.Description, todo.Priority, todo.Completed,
+                todo.CreatedAt, todo.UpdatedAt, todo.DueDate, todo.SupportingArtifact,
+                todo.SpreadsheetID, todo.RowID, todo.UserID,
+                todo.RecurrenceRule, todo.RecurrenceEnd, todo.ParentID)
+            return err
+        })
+        if execErr != nil {
+            results = append(results, BulkItemResult{Status: "error", Error: execErr.Error()})
+            failed = true
+            failedCount++
+            continue
+        }
+
+        if err := withBulkItemSavepoint(tx, i, func() error {
+            return recordTodoAudit(tx, userID.(string), todo.ID, "create", todoCreateDiff(item))
+        }); err != nil {
+            log.Printf("failed to record audit entry for todo %s: %v", todo.ID, err)
+        }
+
+        results = append(results, BulkItemResult{ID: todo.ID, Status: "created"})
+    }
+
+    if failed && isBulkAtomic(c) {
+        tx.Rollback()
+        if err := refundCreateQuota(userID.(string), len(req.Todos)); err != nil {
+            log.Printf("failed to refund create quota for user %s: %v", userID.(string), err)
+        }
+        c.JSON(http.StatusConflict, gin.H{"results": results, "error": "One or more items failed; rolled back due to atomic=true"})
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit bulk create"})
+        return
+    }
+
+    if failedCount > 0 {
+        if err := refundCreateQuota(userID.(string), failedCount); err != nil {
+            log.Printf("failed to refund create quota for user %s: %v", userID.(string), err)
+        }
+    }
+
+    status := http.StatusCreated
+    if failed {
+        status = http.StatusMultiStatus
+    }
+    c.JSON(status, gin.H{"results": results})
+}
+
+// patchTodosBulk partially updates many todos in a single transaction,
+// selected either by an explicit ID list or by a spreadsheet_id+priority
+// filter. A partial failure does not roll back the items that succeeded
+// unless the caller passes ?atomic=true.
+func patchTodosBulk(c *gin.Context) {
+    var req BulkPatchRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    userID, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+        return
+    }
+
+    if len(req.IDs) == 0 && req.Filter == nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Either ids or filter must be provided"})
+        return
+    }
+    if req.Updates.Priority != nil && !validTodoPriority(*req.Updates.Priority) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Priority must be 'low', 'medium', or 'high'"})
+        return
+    }
+    if req.Updates.RecurrenceRule != nil {
+        if _, err := parseRRule(*req.Updates.RecurrenceRule); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+    }
+
+    ids := req.IDs
+    if len(ids) == 0 {
+        query := `SELECT id FROM todos WHERE user_id = $1 AND spreadsheet_id = $2 AND priority = $3 AND deleted_at IS NULL`
+        if err := db.Select(&ids, query, userID.(string), req.Filter.SpreadsheetID, req.Filter.Priority); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve filter"})
+            return
+        }
+    }
+
+    tx, err := db.Beginx()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+        return
+    }
+
+    results := make([]BulkItemResult, 0, len(ids))
+    failed := false
+
+    for i, id := range ids {
+        updates, updateArgs := buildTodoUpdateSet(req.Updates, 3)
+        if len(updates) == 0 {
+            results = append(results, BulkItemResult{ID: id, Status: "error", Error: "No fields to update"})
+            failed = true
+            continue
+        }
+        updates = append(updates, "updated_at = CURRENT_TIMESTAMP")
+        args := append([]interface{}{id, userID.(string)}, updateArgs...)
+
+        query := `
+            UPDATE todos
+            SET ` + strings.Join(updates, ", ") + `
+            WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+
+        var rowsAffected int64
+        execErr := withBulkItemSavepoint(tx, i, func() error {
+            result, err := tx.Exec(query, args...)
+            if err != nil {
+                return err
+            }
+            rowsAffected, _ = result.RowsAffected()
+            return nil
+        })
+        if execErr != nil {
+            results = append(results, BulkItemResult{ID: id, Status: "error", Error: execErr.Error()})
+            failed = true
+            continue
+        }
+        if rowsAffected == 0 {
+            results = append(results, BulkItemResult{ID: id, Status: "error", Error: "Todo not found"})
+            failed = true
+            continue
+        }
+
+        if err := withBulkItemSavepoint(tx, i, func() error {
+            return recordTodoAudit(tx, userID.(string), id, "update", todoUpdateDiff(req.Updates))
+        }); err != nil {
+            log.Printf("failed to record audit entry for todo %s: %v", id, err)
+        }
+
+        results = append(results, BulkItemResult{ID: id, Status: "updated"})
+    }
+
+    if failed && isBulkAtomic(c) {
+        tx.Rollback()
+        c.JSON(http.StatusConflict, gin.H{"results": results, "error": "One or more items failed; rolled back due to atomic=true"})
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit bulk update"})
+        return
+    }
+
+    status := http.StatusOK
+    if failed {
+        status = http.StatusMultiStatus
+    }
+    c.JSON(status, gin.H{"results": results})
+}
+
+// deleteTodosBulk soft-deletes many todos by ID in a single transaction. A
+// partial failure does not roll back the items that succeeded unless the
+// caller passes ?atomic=true.
+func deleteTodosBulk(c *gin.Context) {
+    var req BulkDeleteRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    userID, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+        return
+    }
+
+    if len(req.IDs) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "No ids provided"})
+        return
+    }
+
+    tx, err := db.Beginx()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+        return
+    }
+
+    results := make([]BulkItemResult, 0, len(req.IDs))
+    failed := false
+
+    for i, id := range req.IDs {
+        var rowsAffected int64
+        execErr := withBulkItemSavepoint(tx, i, func() error {
+            result, err := tx.Exec(
+                `UPDATE todos SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`,
+                id, userID.(string))
+            if err != nil {
+                return err
+            }
+            rowsAffected, _ = result.RowsAffected()
+            return nil
+        })
+        if execErr != nil {
+            results = append(results, BulkItemResult{ID: id, Status: "error", Error: execErr.Error()})
+            failed = true
+            continue
+        }
+        if rowsAffected == 0 {
+            results = append(results, BulkItemResult{ID: id, Status: "error", Error: "Todo not found"})
+            failed = true
+            continue
+        }
+
+        if err := withBulkItemSavepoint(tx, i, func() error {
+            return recordTodoAudit(tx, userID.(string), id, "delete", map[string]interface{}{"deleted": true})
+        }); err != nil {
+            log.Printf("failed to record audit entry for todo %s: %v", id, err)
+        }
+
+        results = append(results, BulkItemResult{ID: id, Status: "deleted"})
+    }
+
+    if failed && isBulkAtomic(c) {
+        tx.Rollback()
+        c.JSON(http.StatusConflict, gin.H{"results": results, "error": "One or more items failed; rolled back due to atomic=true"})
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit bulk delete"})
+        return
+    }
+
+    status := http.StatusOK
+    if failed {
+        status = http.StatusMultiStatus
+    }
+    c.JSON(status, gin.H{"results": results})
+}
+// Defaults and bounds for the cursor-paginated todo list endpoints.
+const (
+    defaultTodoPageLimit = 50
+    maxTodoPageLimit     = 200
+)
+
+// todoSortColumns whitelists the columns list endpoints may sort by, so a
+// column name from the query string can never be interpolated into SQL
+// without being checked first.
+var todoSortColumns = map[string]bool{
+    "created_at": true,
+    "updated_at": true,
+    "due_date":   true,
+    "priority":   true,
+}
+
+// TodoPage is the paginated response shape for todo list endpoints.
+type TodoPage struct {
+    Items      []Todo  `json:"items"`
+    NextCursor *string `json:"next_cursor,omitempty"`
+}
+
+// todoCursor is the decoded form of the opaque, base64-encoded pagination
+// cursor. Keyset pagination requires the cursor's comparison columns to
+// match the ORDER BY columns, so the cursor carries the sort_column it was
+// issued for along with the row's value for that column (SortValue) and its
+// id as the tiebreaker.
+type todoCursor struct {
+    SortColumn string `json:"sort_column"`
+    SortValue  string `json:"sort_value"`
+    ID         string `json:"id"`
+}
+
+// encodeTodoCursor builds a cursor for t keyed on sortColumn, the column the
+// page it ends was ordered by.
+func encodeTodoCursor(t Todo, sortColumn string) string {
+    cur := todoCursor{SortColumn: sortColumn, ID: t.ID}
+    switch sortColumn {
+    case "due_date":
+        if t.DueDate != nil {
+            cur.SortValue = t.DueDate.Format(time.RFC3339Nano)
+        }
+    case "updated_at":
+        cur.SortValue = t.UpdatedAt.Format(time.RFC3339Nano)
+    case "priority":
+        cur.SortValue = t.Priority
+    default:
+        cur.SortValue = t.CreatedAt.Format(time.RFC3339Nano)
+    }
+    raw, _ := json.Marshal(cur)
+    return base64.URLEncoding.EncodeToString(raw)
+}
+
+func decodeTodoCursor(s string) (*todoCursor, error) {
+    raw, err := base64.URLEncoding.DecodeString(s)
+    if err != nil {
+        return nil, fmt.Errorf("invalid cursor")
+    }
+    var cur todoCursor
+    if err := json.Unmarshal(raw, &cur); err != nil {
+        return nil, fmt.Errorf("invalid cursor")
+    }
+    return &cur, nil
+}
+
+// todoListParams holds the parsed, validated query parameters shared by the
+// spreadsheet and row todo list endpoints.
+type todoListParams struct {
+    Limit      int
+    Cursor     *todoCursor
+    CursorArg  interface{} // Cursor.SortValue parsed into SortColumn's native comparable type
+    SortColumn string
+    SortOrder  string
+    Completed  *bool
+    Priority   string
+    DueBefore  *time.Time
+    DueAfter   *time.Time
+    Search     string
+}
+
+// parseTodoListParams parses and validates the limit, cursor, sort, and
+// filter query parameters for the todo list endpoints. Unknown sort columns
+// are rejected rather than interpolated, since sort_column ends up directly
+// in the ORDER BY clause.
+func parseTodoListParams(c *gin.Context) (*todoListParams, error) {
+    params := &todoListParams{
+        Limit:      defaultTodoPageLimit,
+        SortColumn: "created_at",
+        SortOrder:  "desc",
+    }
+
+    if limitStr := c.Query("limit"); limitStr != "" {
+        n, err := strconv.Atoi(limitStr)
+        if err != nil || n <= 0 {
+            return nil, fmt.Errorf("invalid limit: %q", limitStr)
+        }
+        if n > maxTodoPageLimit {
+            n = maxTodoPageLimit
+        }
+        params.Limit = n
+    }
+
+    if sortColumn := c.Query("sort_column"); sortColumn != "" {
+        if !todoSortColumns[sortColumn] {
+            return nil, fmt.Errorf("invalid sort_column: %q", sortColumn)
+        }
+        params.SortColumn = sortColumn
+    }
+
+    if sortOrder := strings.ToLower(c.Query("sort_order")); sortOrder != "" {
+        if sortOrder != "asc" && sortOrder != "desc" {
+            return nil, fmt.Errorf("invalid sort_order: %q", sortOrder)
+        }
+        params.SortOrder = sortOrder
+    }
+
+    if cursorStr := c.Query("cursor"); cursorStr != "" {
+        cur, err := decodeTodoCursor(cursorStr)
+        if err != nil {
+            return nil, err
+        }
+        if cur.SortColumn != params.SortColumn {
+            return nil, fmt.Errorf("cursor was issued for sort_column %q, not %q", cur.SortColumn, params.SortColumn)
+        }
+        params.Cursor = cur
+        if params.SortColumn == "priority" {
+            params.CursorArg = cur.SortValue
+        } else {
+            t, err := time.Parse(time.RFC3339Nano, cur.SortValue)
+            if err != nil {
+                return nil, fmt.Errorf("invalid cursor")
+            }
+            params.CursorArg = t
+        }
+    }
+
+    if completedStr := c.Query("completed"); completedStr != "" {
+        completed, err := strconv.ParseBool(completedStr)
+        if err != nil {
+            return nil, fmt.Errorf("invalid completed: %q", completedStr)
+        }
+        params.Completed = &completed
+    }
+
+    if priority := c.Query("priority"); priority != "" {
+        if !validTodoPriority(priority) {
+            return nil, fmt.Errorf("invalid priority: %q", priority)
+        }
+        params.Priority = priority
+    }
+
+    if dueBefore := c.Query("due_before"); dueBefore != "" {
+        t, err := time.Parse(time.RFC3339, dueBefore)
+        if err != nil {
+            return nil, fmt.Errorf("invalid due_before: %q", dueBefore)
+        }
+        params.DueBefore = &t
+    }
+
+    if dueAfter := c.Query("due_after"); dueAfter != "" {
+        t, err := time.Parse(time.RFC3339, dueAfter)
+        if err != nil {
+            return nil, fmt.Errorf("invalid due_after: %q", dueAfter)
+        }
+        params.DueAfter = &t
+    }
+
+    params.Search = c.Query("q")
+
+    return params, nil
+}
+
+// buildTodoListQuery assembles a SELECT against todos with baseWhere (the
+// endpoint's own scoping condition, e.g. spreadsheet/row/user) plus the
+// filters, sort, and cursor from params. It fetches one row past the page
+// limit so the caller can tell whether a next page exists.
+func buildTodoListQuery(baseWhere string, baseArgs []interface{}, params *todoListParams) (string, []interface{}) {
+    conditions := []string{baseWhere, "deleted_at IS NULL"}
+    args := append([]interface{}{}, baseArgs...)
+    argIndex := len(args) + 1
+
+    if params.Completed != nil {
+        conditions = append(conditions, fmt.Sprintf("completed = $%d", argIndex))
+        args = append(args, *params.Completed)
+        argIndex++
+    }
+    if params.Priority != "" {
+        conditions = append(conditions, fmt.Sprintf("priority = $%d", argIndex))
+        args = append(args, params.Priority)
+        argIndex++
+    }
+    if params.DueBefore != nil {
+        conditions = append(conditions, fmt.Sprintf("due_date < $%d", argIndex))
+        args = append(args, *params.DueBefore)
+        argIndex++
+    }
+    if params.DueAfter != nil {
+        conditions = append(conditions, fmt.Sprintf("due_date > $%d", argIndex))
+        args = append(args, *params.DueAfter)
+        argIndex++
+    }
+    if params.Search != "" {
+        conditions = append(conditions, fmt.Sprintf("(title ILIKE $%d OR description ILIKE $%d)", argIndex, argIndex))
+        args = append(args, "%"+params.Search+"%")
+        argIndex++
+    }
+    if params.Cursor != nil {
+        cmp := "<"
+        if params.SortOrder == "asc" {
+            cmp = ">"
+        }
+        conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d)", params.SortColumn, cmp, argIndex, argIndex+1))
+        args = append(args, params.CursorArg, params.Cursor.ID)
+        argIndex += 2
+    }
+
+    query := fmt.Sprintf(`
+        SELECT * FROM todos
+        WHERE %s
+        ORDER BY %s %s, id %s
+        LIMIT $%d`,
+        strings.Join(conditions, " AND "), params.SortColumn, params.SortOrder, params.SortOrder, argIndex)
+    args = append(args, params.Limit+1)
+
+    return query, args
+}
+
+// paginateTodos trims a limit+1-sized result set down to a page of results
+// plus an opaque next_cursor, or no cursor if that was the last page.
+func paginateTodos(todos []Todo, limit int, sortColumn string) TodoPage {
+    page := TodoPage{Items: todos}
+    if page.Items == nil {
+        page.Items = []Todo{}
+    }
+    if len(page.Items) > limit {
+        cursor := encodeTodoCursor(page.Items[limit-1], sortColumn)
+        page.Items = page.Items[:limit]
+        page.NextCursor = &cursor
+    }
+    return page
+}
+
+// todoAuditExecer is satisfied by both *sqlx.DB and *sqlx.Tx, so audit
+// entries can be written standalone or as part of an existing transaction.
+type todoAuditExecer interface {
+    Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// recordTodoAudit writes a todo_audit row capturing who did what to a todo
+// and when, with a JSONB diff of the columns that changed.
+func recordTodoAudit(exec todoAuditExecer, userID, todoID, action string, diff map[string]interface{}) error {
+    payload, err := json.Marshal(diff)
+    if err != nil {
+        return err
+    }
+
+    _, err = exec.Exec(
+        `INSERT INTO todo_audit (id, todo_id, user_id, action, diff, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+        uuid.New().String(), todoID, userID, action, payload, time.Now())
+    return err
+}
+
+// todoCreateDiff captures the fields a CreateTodoRequest set, for the audit log.
+func todoCreateDiff(req CreateTodoRequest) map[string]interface{} {
+    diff := map[string]interface{}{
+        "title":          req.Title,
+        "priority":       req.Priority,
+        "spreadsheet_id": req.SpreadsheetID,
+    }
+    if req.Description != "" {
+        diff["description"] = req.Description
+    }
+    if req.DueDate != nil {
+        diff["due_date"] = *req.DueDate
+    }
+    if req.RowID != nil {
+        diff["row_id"] = *req.RowID
+    }
+    if req.RecurrenceRule != nil {
+        diff["recurrence_rule"] = *req.RecurrenceRule
+    }
+    return diff
+}
+
+// todoUpdateDiff captures which fields an UpdateTodoRequest changed, for the audit log.
+func todoUpdateDiff(req UpdateTodoRequest) map[string]interface{} {
+    diff := map[string]interface{}{}
+    if req.Title != nil {
+        diff["title"] = *req.Title
+    }
+    if req.Description != nil {
+        diff["description"] = *req.Description
+    }
+    if req.Priority != nil {
+        diff["priority"] = *req.Priority
+    }
+    if req.Completed != nil {
+        diff["completed"] = *req.Completed
+    }
+    if req.DueDate != nil {
+        diff["due_date"] = *req.DueDate
+    }
+    if req.SupportingArtifact != nil {
+        diff["supporting_artifact"] = *req.SupportingArtifact
+    }
+    if req.RecurrenceRule != nil {
+        diff["recurrence_rule"] = *req.RecurrenceRule
+    }
+    if req.RecurrenceEnd != nil {
+        diff["recurrence_end"] = *req.RecurrenceEnd
+    }
+    return diff
+}
+
+// getTodoEvents upgrades to an SSE stream of created/updated/completed/deleted
+// events for a spreadsheet, scoped to the caller's user_id, so collaborative
+// spreadsheet UIs can reflect teammates' todo changes without polling
+// getTodoStats on a timer.
+func getTodoEvents(c *gin.Context) {
+    spreadsheetID := c.Param("spreadsheet_id")
+    userIDVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+        return
+    }
+    userID := userIDVal.(string)
+
+    _, events, unsubscribe := todoEvents.Subscribe(spreadsheetID)
+    defer unsubscribe()
+
+    c.Header("Content-Type", "text/event-stream")
+    c.Header("Cache-Control", "no-cache")
+    c.Header("Connection", "keep-alive")
+
+    heartbeat := time.NewTicker(20 * time.Second)
+    defer heartbeat.Stop()
+
+    c.Stream(func(w io.Writer) bool {
+        select {
+        case event, ok := <-events:
+            if !ok {
+                return false
+            }
+            if event.UserID != userID {
+                return true
+            }
+            payload, err := json.Marshal(event)
+            if err != nil {
+                log.Printf("failed to encode todo event for SSE: %v", err)
+                return true
+            }
+            c.SSEvent(event.Type, string(payload))
+            return true
+        case <-heartbeat.C:
+            c.SSEvent("ping", "")
+            return true
+        case <-c.Request.Context().Done():
+            return false
+        }
+    })
+}
+
+// TodoEvent is a single pub/sub message published to SSE subscribers after a
+// todo mutation commits.
+type TodoEvent struct {
+    Type          string `json:"type"` // created, updated, completed, deleted
+    SpreadsheetID string `json:"spreadsheet_id"`
+    UserID        string `json:"user_id"`
+    Todo          *Todo  `json:"todo,omitempty"`
+    TodoID        string `json:"todo_id,omitempty"`
+}
+
+// todoEventHub fans out todo events to SSE subscribers, scoped by
+// spreadsheet. Implementations must be safe for concurrent use.
+type todoEventHub interface {
+    Subscribe(spreadsheetID string) (connID string, events <-chan TodoEvent, unsubscribe func())
+    Publish(event TodoEvent)
+}
+
+// todoEvents is the process-wide hub that handlers publish to and the SSE
+// endpoint subscribes from. Swap in newPostgresTodoEventHub at startup for
+// multi-replica deployments so events fan out across servers.
+var todoEvents todoEventHub = newMemoryTodoEventHub()
+
+// memoryTodoEventHub is an in-memory pub/sub hub scoped to a single server
+// process; it does not fan out across replicas. See postgresTodoEventHub for
+// the multi-replica variant.
+type memoryTodoEventHub struct {
+    mu   sync.Mutex
+    subs map[string]map[string]chan TodoEvent
+}
+
+func newMemoryTodoEventHub() *memoryTodoEventHub {
+    return &memoryTodoEventHub{subs: make(map[string]map[string]chan TodoEvent)}
+}
+
+func (h *memoryTodoEventHub) Subscribe(spreadsheetID string) (string, <-chan TodoEvent, func()) {
+    connID := uuid.New().String()
+    ch := make(chan TodoEvent, 16)
+
+    h.mu.Lock()
+    if h.subs[spreadsheetID] == nil {
+        h.subs[spreadsheetID] = make(map[string]chan TodoEvent)
+    }
+    h.subs[spreadsheetID][connID] = ch
+    h.mu.Unlock()
+
+    unsubscribe := func() {
+        h.mu.Lock()
+        defer h.mu.Unlock()
+        if conns, ok := h.subs[spreadsheetID]; ok {
+            delete(conns, connID)
+            close(ch)
+            if len(conns) == 0 {
+                delete(h.subs, spreadsheetID)
+            }
+        }
+    }
+
+    return connID, ch, unsubscribe
+}
+
+func (h *memoryTodoEventHub) Publish(event TodoEvent) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    for _, ch := range h.subs[event.SpreadsheetID] {
+        select {
+        case ch <- event:
+        default:
+            // Slow consumer; drop rather than block the publisher.
+        }
+    }
+}
+
+// pgTodoChannel derives the Postgres NOTIFY channel name for a spreadsheet.
+// Channel names can't contain hyphens without quoting, so UUID hyphens are
+// replaced with underscores.
+func pgTodoChannel(spreadsheetID string) string {
+    return "todos_" + strings.ReplaceAll(spreadsheetID, "-", "_")
+}
+
+// pgTodoNotification is the payload sent over LISTEN/NOTIFY: the event plus
+// the id of the replica that published it, so relay can recognize and skip
+// its own round-tripped notifications instead of re-publishing them to the
+// local subscribers Publish already delivered to directly.
+type pgTodoNotification struct {
+    OriginID string    `json:"origin_id"`
+    Event    TodoEvent `json:"event"`
+}
+
+// postgresTodoEventHub fans todo events out across replicas using Postgres
+// LISTEN/NOTIFY: Publish calls pg_notify on the spreadsheet's channel (an
+// AFTER trigger could emit the same notification for writes that bypass this
+// service), and each replica LISTENs and re-publishes locally through an
+// embedded in-memory hub so its own SSE subscribers see the event.
+type postgresTodoEventHub struct {
+    *memoryTodoEventHub
+    listener *pq.Listener
+    originID string
+
+    mu        sync.Mutex
+    listenRef map[string]int
+}
+
+// newPostgresTodoEventHub starts a pq.Listener against connStr. Use this
+// instead of the default in-memory hub when running more than one API
+// replica.
+func newPostgresTodoEventHub(connStr string) (*postgresTodoEventHub, error) {
+    hub := &postgresTodoEventHub{
+        memoryTodoEventHub: newMemoryTodoEventHub(),
+        originID:           uuid.New().String(),
+        listenRef:          make(map[string]int),
+    }
+
+    hub.listener = pq.NewListener(connStr, 10*time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+        if err != nil {
+            log.Printf("todo event listener error: %v", err)
+        }
+    })
+
+    go hub.relay()
+
+    return hub, nil
+}
+
+func (h *postgresTodoEventHub) relay() {
+    for notification := range h.listener.Notify {
+        if notification == nil {
+            continue
+        }
+        var n pgTodoNotification
+        if err := json.Unmarshal([]byte(notification.Extra), &n); err != nil {
+            log.Printf("failed to decode todo event notification: %v", err)
+            continue
+        }
+        if n.OriginID == h.originID {
+            // Round-tripped copy of a notification this replica sent itself;
+            // Publish already delivered it to local subscribers.
+            continue
+        }
+        h.memoryTodoEventHub.Publish(n.Event)
+    }
+}
+
+func (h *postgresTodoEventHub) Subscribe(spreadsheetID string) (string, <-chan TodoEvent, func()) {
+    h.mu.Lock()
+    h.listenRef[spreadsheetID]++
+    if h.listenRef[spreadsheetID] == 1 {
+        if err := h.listener.Listen(pgTodoChannel(spreadsheetID)); err != nil {
+            log.Printf("failed to LISTEN for spreadsheet %s: %v", spreadsheetID, err)
+        }
+    }
+    h.mu.Unlock()
+
+    connID, events, unsubscribe := h.memoryTodoEventHub.Subscribe(spreadsheetID)
+
+    return connID, events, func() {
+        unsubscribe()
+
+        h.mu.Lock()
+        defer h.mu.Unlock()
+        h.listenRef[spreadsheetID]--
+        if h.listenRef[spreadsheetID] <= 0 {
+            delete(h.listenRef, spreadsheetID)
+            if err := h.listener.Unlisten(pgTodoChannel(spreadsheetID)); err != nil {
+                log.Printf("failed to UNLISTEN for spreadsheet %s: %v", spreadsheetID, err)
+            }
+        }
+    }
+}
+
+// Publish notifies Postgres (for other replicas) and publishes locally, so
+// this replica's own subscribers don't wait on the round-trip. The
+// notification is tagged with this replica's origin id so relay() can tell
+// its own round-tripped copy apart from one another replica sent, and skip
+// it rather than delivering the event to local subscribers twice.
+func (h *postgresTodoEventHub) Publish(event TodoEvent) {
+    h.memoryTodoEventHub.Publish(event)
+
+    payload, err := json.Marshal(pgTodoNotification{OriginID: h.originID, Event: event})
+    if err != nil {
+        log.Printf("failed to encode todo event: %v", err)
+        return
+    }
+    if _, err := db.Exec(`SELECT pg_notify($1, $2)`, pgTodoChannel(event.SpreadsheetID), string(payload)); err != nil {
+        log.Printf("failed to notify todo event: %v", err)
+    }
+}
+
+// UserQuota tracks a user's todo plan limits and usage for the current
+// billing period.
+type UserQuota struct {
+    UserID             string    `json:"user_id" db:"user_id"`
+    Plan               string    `json:"plan" db:"plan"`
+    MonthlyCreateLimit int       `json:"monthly_create_limit" db:"monthly_create_limit"`
+    ActiveLimit        int       `json:"active_limit" db:"active_limit"`
+    PeriodStart        time.Time `json:"period_start" db:"period_start"`
+    CreatedCount       int       `json:"created_count" db:"created_count"`
+}
+
+const (
+    defaultQuotaPlan          = "free"
+    defaultMonthlyCreateLimit = 200
+    defaultActiveLimit        = 500
+    quotaPeriod               = 30 * 24 * time.Hour
+)
+
+// ensureUserQuota fetches the caller's quota row, provisioning a default
+// free-plan row on first use, and rolls the usage counter over into a new
+// period once the previous one has elapsed.
+func ensureUserQuota(userID string) (*UserQuota, error) {
+    var quota UserQuota
+    err := db.Get(&quota, `SELECT * FROM user_quotas WHERE user_id = $1`, userID)
+    if err == sql.ErrNoRows {
+        err = db.Get(&quota, `
+            INSERT INTO user_quotas (user_id, plan, monthly_create_limit, active_limit, period_start, created_count)
+            VALUES ($1, $2, $3, $4, $5, 0)
+            ON CONFLICT (user_id) DO UPDATE SET plan = user_quotas.plan
+            RETURNING *`,
+            userID, defaultQuotaPlan, defaultMonthlyCreateLimit, defaultActiveLimit, time.Now())
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if time.Since(quota.PeriodStart) >= quotaPeriod {
+        if err := db.Get(&quota, `
+            UPDATE user_quotas
+            SET period_start = $2, created_count = 0
+            WHERE user_id = $1
+            RETURNING *`, userID, time.Now()); err != nil {
+            return nil, err
+        }
+    }
+
+    return &quota, nil
+}
+
+// activeTodoCount returns how many non-completed, non-deleted todos the
+// user currently owns, for comparison against UserQuota.ActiveLimit.
+func activeTodoCount(userID string) (int, error) {
+    var count int
+    err := db.Get(&count, `
+        SELECT COUNT(*) FROM todos
+        WHERE user_id = $1 AND completed = false AND deleted_at IS NULL`, userID)
+    return count, err
+}
+
+// refundCreateQuota gives back n create-quota units charged by
+// quotaMiddleware for items that didn't end up being created, e.g. bulk
+// create items that failed validation or insertion. created_count is
+// clamped at 0 so a late or duplicate refund can't push it negative.
+func refundCreateQuota(userID string, n int) error {
+    if n <= 0 {
+        return nil
+    }
+    _, err := db.Exec(`
+        UPDATE user_quotas
+        SET created_count = GREATEST(created_count - $2, 0)
+        WHERE user_id = $1`, userID, n)
+    return err
+}
+
+// setQuotaHeaders surfaces remaining-create-quota and reset-time headers on
+// every quota-checked response, success or failure.
+func setQuotaHeaders(c *gin.Context, quota *UserQuota) {
+    remaining := quota.MonthlyCreateLimit - quota.CreatedCount
+    if remaining < 0 {
+        remaining = 0
+    }
+    c.Header("X-Quota-Remaining", strconv.Itoa(remaining))
+    c.Header("X-Quota-Reset", quota.PeriodStart.Add(quotaPeriod).Format(time.RFC3339))
+}
+
+// writeQuotaExceeded aborts the request with 429 Too Many Requests once a
+// plan limit has been hit.
+func writeQuotaExceeded(c *gin.Context, quota *UserQuota) {
+    setQuotaHeaders(c, quota)
+    c.JSON(http.StatusTooManyRequests, gin.H{"error": "Todo quota exceeded for this billing period"})
+    c.Abort()
+}
+
+// pendingCreateCount reports how many todos the current POST request is
+// about to create: 1 for the single-item create endpoint, or the length of
+// the "todos" array for POST /todos/bulk. It peeks the bulk request body to
+// get the count, then restores it so createTodosBulk can still bind it.
+func pendingCreateCount(c *gin.Context) (int, error) {
+    if !strings.HasSuffix(c.FullPath(), "/bulk") {
+        return 1, nil
+    }
+
+    body, err := io.ReadAll(c.Request.Body)
+    if err != nil {
+        return 0, fmt.Errorf("failed to read request body")
+    }
+    c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+    var req BulkCreateRequest
+    if err := json.Unmarshal(body, &req); err != nil {
+        return 0, fmt.Errorf("invalid request body")
+    }
+    if len(req.Todos) == 0 {
+        return 0, fmt.Errorf("no todos provided")
+    }
+    return len(req.Todos), nil
+}
+
+// quotaMiddleware enforces the caller's monthly-create and active-todo
+// limits before a mutating todo request reaches its handler. It piggybacks
+// on the same "user_id" context value the handlers already rely on. Only
+// POST requests (single and bulk create) consume create quota and are
+// checked against the active-todo limit; PUT/PATCH/DELETE requests pass
+// through once the quota row has been loaded, but still get the usage
+// headers set so clients can track consumption on every mutation. Bulk
+// create consumes one quota unit per todo in the payload, checked against
+// both limits up front, rather than one unit for the whole request -
+// otherwise a single bulk call could create hundreds of todos past
+// active_limit for the cost of one create. createTodosBulk is non-atomic by
+// default and reports per-item failures, so it refunds (via
+// refundCreateQuota) the units charged here for items that didn't end up
+// created - the whole payload on an atomic=true rollback, or just the
+// failed items otherwise.
+func quotaMiddleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        userIDVal, exists := c.Get("user_id")
+        if !exists {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+            c.Abort()
+            return
+        }
+        userID := userIDVal.(string)
+
+        quota, err := ensureUserQuota(userID)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load quota"})
+            c.Abort()
+            return
+        }
+
+        if c.Request.Method == http.MethodPost {
+            itemCount, err := pendingCreateCount(c)
+            if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                c.Abort()
+                return
+            }
+
+            activeCount, err := activeTodoCount(userID)
+            if err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check active todo count"})
+                c.Abort()
+                return
+            }
+
+            if quota.CreatedCount+itemCount > quota.MonthlyCreateLimit || activeCount+itemCount > quota.ActiveLimit {
+                writeQuotaExceeded(c, quota)
+                return
+            }
+
+            if err := db.Get(quota, `
+                UPDATE user_quotas
+                SET created_count = created_count + $2
+                WHERE user_id = $1 AND created_count + $2 <= monthly_create_limit
+                RETURNING *`, userID, itemCount); err != nil {
+                writeQuotaExceeded(c, quota)
+                return
+            }
+        }
+
+        setQuotaHeaders(c, quota)
+        c.Next()
+    }
+}
+
+// getTodoQuota reports the caller's current plan limits and usage so
+// clients can render a quota indicator.
+func getTodoQuota(c *gin.Context) {
+    userIDVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+        return
+    }
+    userID := userIDVal.(string)
+
+    quota, err := ensureUserQuota(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load quota"})
+        return
+    }
+
+    activeCount, err := activeTodoCount(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check active todo count"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "plan":                 quota.Plan,
+        "monthly_create_limit": quota.MonthlyCreateLimit,
+        "created_this_period":  quota.CreatedCount,
+        "active_limit":         quota.ActiveLimit,
+        "active_count":         activeCount,
+        "period_start":         quota.PeriodStart,
+        "period_reset":         quota.PeriodStart.Add(quotaPeriod),
+    })
+}